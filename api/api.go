@@ -3,7 +3,8 @@
 package api
 
 import (
-	"net/http"
+	"context"
+	"time"
 
 	"github.com/Safing/safing-core/log"
 	"github.com/Safing/safing-core/modules"
@@ -11,17 +12,23 @@ import (
 
 var apiModule *modules.Module
 
+// shutdownGracePeriod bounds how long Start, once told to stop, waits for in-flight requests to drain. The listener is closed at once; if requests are still running when the period expires, Start logs a warning and reports the module as stopped without closing their connections.
+const shutdownGracePeriod = 3 * time.Second
+
+// Start starts the API module and blocks until it is told to stop.
 func Start() {
 	apiModule = modules.Register("Api", 32)
 
-	go run()
+	srv := newServer(NewRouter())
+	server = srv
+
+	go srv.run()
 
 	<-apiModule.Stop
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownGracePeriod)
+	defer cancel()
+	if err := srv.shutdown(ctx); err != nil {
+		log.Warningf("api: shutdown did not complete cleanly: %s", err)
+	}
 	apiModule.StopComplete()
 }
-
-func run() {
-	router := NewRouter()
-
-	log.Infof("%s", http.ListenAndServe(":18", router))
-}
@@ -0,0 +1,114 @@
+// Copyright Safing ICS Technologies GmbH. Use of this source code is governed by the AGPL license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/Safing/safing-core/configuration"
+	"github.com/Safing/safing-core/log"
+)
+
+const (
+	defaultListenAddress = "127.0.0.1:18171"
+	defaultReadTimeout   = 10 * time.Second
+	defaultWriteTimeout  = 10 * time.Second
+	defaultIdleTimeout   = 60 * time.Second
+)
+
+// server is the single, running API Server instance, set by Start.
+var server *Server
+
+// Server serves the API over HTTP(S), with optional bearer-token auth and graceful shutdown.
+type Server struct {
+	mux       *http.ServeMux
+	http      *http.Server
+	authToken string
+	ready     int32
+}
+
+// newServer builds a Server from the configuration module. router handles any path not claimed by the built-in /v1/watch endpoint or by a call to RegisterHandler.
+func newServer(router http.Handler) *Server {
+	s := &Server{
+		mux:       http.NewServeMux(),
+		authToken: configuration.GetString("api/authToken", ""),
+	}
+	s.mux.Handle("/", router)
+	s.mux.HandleFunc("/v1/watch", handleWatch)
+
+	s.http = &http.Server{
+		Addr:         configuration.GetString("api/listenAddress", defaultListenAddress),
+		Handler:      s.authenticate(s.mux),
+		ReadTimeout:  defaultReadTimeout,
+		WriteTimeout: defaultWriteTimeout,
+		IdleTimeout:  defaultIdleTimeout,
+	}
+
+	return s
+}
+
+// RegisterHandler registers h to handle requests for path on the running API server. It allows other modules (firewall, nameserver, ...) to contribute endpoints without modifying the router directly.
+func RegisterHandler(path string, h http.Handler) {
+	if server == nil {
+		log.Warningf("api: RegisterHandler(%s) called before the API server was started", path)
+		return
+	}
+	server.mux.Handle(path, h)
+}
+
+// Ready reports whether the API server is currently accepting requests.
+func Ready() bool {
+	return server != nil && atomic.LoadInt32(&server.ready) == 1
+}
+
+// run starts serving the API over TLS, using the configured certificate and key if both are set and a generated self-signed certificate otherwise, and blocks until the listener is closed. It serves plain HTTP only if generating the self-signed certificate fails.
+func (s *Server) run() {
+	certFile := configuration.GetString("api/tlsCertFile", "")
+	keyFile := configuration.GetString("api/tlsKeyFile", "")
+
+	var err error
+	switch {
+	case certFile != "" && keyFile != "":
+		atomic.StoreInt32(&s.ready, 1)
+		err = s.http.ListenAndServeTLS(certFile, keyFile)
+	default:
+		cert, tlsErr := generateSelfSignedCert()
+		if tlsErr != nil {
+			log.Warningf("api: could not generate self-signed certificate, falling back to plain HTTP: %s", tlsErr)
+			atomic.StoreInt32(&s.ready, 1)
+			err = s.http.ListenAndServe()
+		} else {
+			s.http.TLSConfig = &tls.Config{Certificates: []tls.Certificate{cert}}
+			atomic.StoreInt32(&s.ready, 1)
+			err = s.http.ListenAndServeTLS("", "")
+		}
+	}
+
+	atomic.StoreInt32(&s.ready, 0)
+	if err != nil && err != http.ErrServerClosed {
+		log.Warningf("api: server stopped unexpectedly: %s", err)
+	}
+}
+
+// shutdown gracefully stops the server, waiting for in-flight requests to complete or ctx to expire.
+func (s *Server) shutdown(ctx context.Context) error {
+	return s.http.Shutdown(ctx)
+}
+
+// authenticate wraps next with bearer-token authentication. If no token is configured, the API is assumed to be bound to a trusted interface and requests pass through unchecked.
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	if s.authToken == "" {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+s.authToken {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
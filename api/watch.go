@@ -0,0 +1,67 @@
+// Copyright Safing ICS Technologies GmbH. Use of this source code is governed by the AGPL license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"net/http"
+
+	ds "github.com/ipfs/go-datastore"
+	"github.com/pkg/bson"
+
+	"github.com/Safing/safing-core/database"
+	"github.com/Safing/safing-core/log"
+)
+
+// handleWatch upgrades the request to a WebSocket and streams BSON-framed database.Events for everything at or below the "prefix" query parameter, optionally restricted to a "type" model type, until the client disconnects.
+func handleWatch(w http.ResponseWriter, r *http.Request) {
+	prefixParam := r.URL.Query().Get("prefix")
+	if prefixParam == "" {
+		http.Error(w, "missing prefix query parameter", http.StatusBadRequest)
+		return
+	}
+	prefix := ds.NewKey(prefixParam)
+
+	sub, err := database.Subscribe(&prefix, database.SubscribeOptions{
+		ModelType: r.URL.Query().Get("type"),
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer sub.Unsubscribe()
+
+	conn, err := upgradeWebsocket(w, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	defer conn.Close()
+
+	go watchForDisconnect(conn, sub)
+
+	for event := range sub.Events {
+		data, err := bson.Marshal(map[string]interface{}{
+			"op":    int32(event.Op),
+			"key":   event.Key.String(),
+			"model": event.Model,
+		})
+		if err != nil {
+			log.Warningf("api: failed to encode watch event for %s: %s", event.Key, err)
+			continue
+		}
+		if err := conn.WriteMessage(data); err != nil {
+			log.Tracef("api: watch client for %s disconnected: %s", prefix.String(), err)
+			return
+		}
+	}
+}
+
+// watchForDisconnect unblocks the sub.Events loop in handleWatch as soon as the client goes away, since reading from a closed TCP connection is the only reliable way to detect that on a one-way stream.
+func watchForDisconnect(conn *wsConn, sub *database.Subscription) {
+	for {
+		if _, _, err := conn.readFrame(); err != nil {
+			sub.Unsubscribe()
+			return
+		}
+	}
+}
@@ -0,0 +1,146 @@
+// Copyright Safing ICS Technologies GmbH. Use of this source code is governed by the AGPL license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+)
+
+// websocketGUID is the magic value RFC 6455 defines for computing Sec-WebSocket-Accept.
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+const (
+	wsOpBinary = 0x2
+	wsOpClose  = 0x8
+)
+
+// wsConn is a minimal RFC 6455 WebSocket connection. It only supports unfragmented frames and has no ping/pong handling, which is sufficient for the one-way, server-to-client event streams this package produces.
+type wsConn struct {
+	conn net.Conn
+	buf  *bufio.ReadWriter
+}
+
+// upgradeWebsocket performs the WebSocket handshake on r and hands back a wsConn for sending frames to the client.
+func upgradeWebsocket(w http.ResponseWriter, r *http.Request) (*wsConn, error) {
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if key == "" || r.Header.Get("Upgrade") != "websocket" {
+		return nil, errors.New("api: not a websocket upgrade request")
+	}
+
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		return nil, errors.New("api: response writer does not support hijacking")
+	}
+	conn, buf, err := hj.Hijack()
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = buf.WriteString("HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + acceptKey(key) + "\r\n\r\n")
+	if err == nil {
+		err = buf.Flush()
+	}
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return &wsConn{conn: conn, buf: buf}, nil
+}
+
+// acceptKey computes the Sec-WebSocket-Accept value for a given Sec-WebSocket-Key, as defined by RFC 6455.
+func acceptKey(key string) string {
+	h := sha1.New()
+	h.Write([]byte(key + websocketGUID))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// WriteMessage sends data as a single, unfragmented binary WebSocket frame.
+func (c *wsConn) WriteMessage(data []byte) error {
+	header := make([]byte, 1, 10)
+	header[0] = 0x80 | wsOpBinary
+
+	switch {
+	case len(data) <= 125:
+		header = append(header, byte(len(data)))
+	case len(data) <= 65535:
+		ext := make([]byte, 2)
+		binary.BigEndian.PutUint16(ext, uint16(len(data)))
+		header = append(header, 126)
+		header = append(header, ext...)
+	default:
+		ext := make([]byte, 8)
+		binary.BigEndian.PutUint64(ext, uint64(len(data)))
+		header = append(header, 127)
+		header = append(header, ext...)
+	}
+
+	if _, err := c.buf.Write(header); err != nil {
+		return err
+	}
+	if _, err := c.buf.Write(data); err != nil {
+		return err
+	}
+	return c.buf.Flush()
+}
+
+// readFrame reads a single client frame and returns its unmasked payload. The watch handler only uses this to notice that the client has gone away.
+func (c *wsConn) readFrame() (opcode byte, payload []byte, err error) {
+	head := make([]byte, 2)
+	if _, err = io.ReadFull(c.buf, head); err != nil {
+		return 0, nil, err
+	}
+	opcode = head[0] & 0x0f
+	masked := head[1]&0x80 != 0
+	length := int64(head[1] & 0x7f)
+
+	switch length {
+	case 126:
+		ext := make([]byte, 2)
+		if _, err = io.ReadFull(c.buf, ext); err != nil {
+			return 0, nil, err
+		}
+		length = int64(binary.BigEndian.Uint16(ext))
+	case 127:
+		ext := make([]byte, 8)
+		if _, err = io.ReadFull(c.buf, ext); err != nil {
+			return 0, nil, err
+		}
+		length = int64(binary.BigEndian.Uint64(ext))
+	}
+
+	var mask [4]byte
+	if masked {
+		if _, err = io.ReadFull(c.buf, mask[:]); err != nil {
+			return 0, nil, err
+		}
+	}
+
+	payload = make([]byte, length)
+	if _, err = io.ReadFull(c.buf, payload); err != nil {
+		return 0, nil, err
+	}
+	if masked {
+		for i := range payload {
+			payload[i] ^= mask[i%4]
+		}
+	}
+	return opcode, payload, nil
+}
+
+// Close sends a close frame and closes the underlying connection.
+func (c *wsConn) Close() error {
+	c.buf.Write([]byte{0x80 | wsOpClose, 0x00})
+	c.buf.Flush()
+	return c.conn.Close()
+}
@@ -0,0 +1,75 @@
+// Copyright Safing ICS Technologies GmbH. Use of this source code is governed by the AGPL license that can be found in the LICENSE file.
+
+package database
+
+import (
+	"errors"
+
+	ds "github.com/ipfs/go-datastore"
+)
+
+// batchOp is a single queued write, recorded so its subscription event can be fired once the batch it belongs to commits successfully.
+type batchOp struct {
+	op    EventOp
+	key   ds.Key
+	model Model
+}
+
+// batch implements ds.Batch on top of the database facade. If the underlying datastore supports ds.Batching, writes are mirrored into its native batch for an atomic Commit; otherwise Commit degrades to applying the queued operations sequentially.
+type batch struct {
+	ops     []batchOp
+	dsBatch ds.Batch
+}
+
+// Put queues value for the key, to be written on Commit.
+func (b *batch) Put(key ds.Key, value interface{}) error {
+	model, ok := value.(Model)
+	if !ok {
+		return errors.New("database: batch value is not a Model")
+	}
+	b.ops = append(b.ops, batchOp{op: EventUpdate, key: key, model: model})
+	if b.dsBatch != nil {
+		return b.dsBatch.Put(key, value)
+	}
+	return nil
+}
+
+// Delete queues the removal of key, to be applied on Commit.
+func (b *batch) Delete(key ds.Key) error {
+	b.ops = append(b.ops, batchOp{op: EventDelete, key: key})
+	if b.dsBatch != nil {
+		return b.dsBatch.Delete(key)
+	}
+	return nil
+}
+
+// Commit writes all queued operations to the datastore and, only once that succeeds, fires the corresponding subscription events.
+func (b *batch) Commit() error {
+	if b.dsBatch != nil {
+		if err := b.dsBatch.Commit(); err != nil {
+			return err
+		}
+	} else {
+		for _, o := range b.ops {
+			var err error
+			if o.op == EventDelete {
+				err = db.Delete(o.key)
+			} else {
+				err = db.Put(o.key, o.model)
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, o := range b.ops {
+		if o.op == EventDelete {
+			key := o.key
+			handleDeleteSubscriptions(&key)
+		} else {
+			handleUpdateSubscriptions(o.model)
+		}
+	}
+	return nil
+}
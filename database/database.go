@@ -16,13 +16,22 @@ import (
 	"github.com/Safing/safing-core/database/ds/simplefs"
 	"github.com/Safing/safing-core/log"
 	"github.com/Safing/safing-core/meta"
+	"github.com/Safing/safing-core/modules"
 )
 
 // TODO: do not let other modules panic, even if database module crashes.
 var db ds.Datastore
 
+// sfsDB is kept alongside db so Close can flush the on-disk store directly, instead of trusting the channelshim/syncmount wrappers in db to forward Close() down to it.
+var sfsDB interface {
+	ds.Datastore
+	Close() error
+}
+
 var ErrNotFound = errors.New("database: entry could not be found")
 
+var databaseModule *modules.Module
+
 func init() {
 	if strings.HasSuffix(os.Args[0], ".test") {
 		// testing setup
@@ -32,7 +41,7 @@ func init() {
 	}
 
 	var err error
-	sfsDB, err := simplefs.NewDatastore(meta.DatabaseDir())
+	sfsDB, err = simplefs.NewDatastore(meta.DatabaseDir())
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "FATAL ERROR: could not init simplefs database: %s\n", err)
 		os.Exit(1)
@@ -51,15 +60,36 @@ func init() {
 		},
 	}))
 
+	databaseModule = modules.Register("Database", 8)
+	go func() {
+		<-databaseModule.Stop
+		if err := Close(); err != nil {
+			log.Warningf("database: failed to close cleanly: %s", err)
+		}
+		databaseModule.StopComplete()
+	}()
 }
 
-// func Batch() (ds.Batch, error) {
-//   return db.Batch()
-// }
+// Batch returns a ds.Batch for coalescing Create, Update and Delete calls into a single Commit. If the underlying datastore implements ds.Batching, its native batch is used; otherwise Commit falls back to applying the queued operations sequentially. Either way, subscription events are only fired for operations that made it into a successful Commit.
+func Batch() (ds.Batch, error) {
+	b := &batch{}
+	if batching, ok := db.(ds.Batching); ok {
+		dsBatch, err := batching.Batch()
+		if err != nil {
+			return nil, err
+		}
+		b.dsBatch = dsBatch
+	}
+	return b, nil
+}
 
-// func Close() error {
-//   return db.Close()
-// }
+// Close flushes and closes the on-disk datastore. It is called automatically when the database module is stopped as part of modules.InitiateFullShutdown(). In test mode, where only an in-memory map is used, this is a no-op.
+func Close() error {
+	if sfsDB == nil {
+		return nil
+	}
+	return sfsDB.Close()
+}
 
 func Get(key *ds.Key) (Model, error) {
 	data, err := db.Get(*key)
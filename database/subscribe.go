@@ -0,0 +1,145 @@
+// Copyright Safing ICS Technologies GmbH. Use of this source code is governed by the AGPL license that can be found in the LICENSE file.
+
+package database
+
+import (
+	"sync"
+
+	ds "github.com/ipfs/go-datastore"
+
+	"github.com/Safing/safing-core/log"
+)
+
+// subscriberBufferSize bounds how many unconsumed Events a Subscription will queue before it is considered too slow to keep up.
+const subscriberBufferSize = 64
+
+// EventOp describes the kind of change that produced an Event.
+type EventOp uint8
+
+// Event operations.
+const (
+	EventCreate EventOp = iota
+	EventUpdate
+	EventDelete
+)
+
+// Event describes a single change to a Model in the database.
+type Event struct {
+	Op    EventOp
+	Key   ds.Key
+	Model Model
+}
+
+// SubscribeOptions configures a Subscribe call.
+type SubscribeOptions struct {
+	// ModelType, if set, restricts the Subscription to Events whose Model has this type name, as returned by getTypeName.
+	ModelType string
+}
+
+// Subscription delivers a stream of Events for keys at or below the prefix given to Subscribe.
+type Subscription struct {
+	prefix *ds.Key
+	opts   SubscribeOptions
+
+	// Events carries an Event for every Create, Update and Delete matching the Subscription. If the subscriber does not keep up, the Subscription is dropped and Events is closed.
+	Events <-chan Event
+	events chan Event
+
+	dropped bool
+	closed  bool
+}
+
+var (
+	subscriptionsLock sync.Mutex
+	subscriptions     []*Subscription
+)
+
+// Subscribe returns a Subscription that receives an Event for every Create, Update and Delete at or below prefix.
+func Subscribe(prefix *ds.Key, opts SubscribeOptions) (*Subscription, error) {
+	events := make(chan Event, subscriberBufferSize)
+	sub := &Subscription{
+		prefix: prefix,
+		opts:   opts,
+		Events: events,
+		events: events,
+	}
+
+	subscriptionsLock.Lock()
+	defer subscriptionsLock.Unlock()
+	subscriptions = append(subscriptions, sub)
+
+	return sub, nil
+}
+
+// Unsubscribe stops delivery of further Events and releases the Subscription. It is safe to call more than once.
+func (s *Subscription) Unsubscribe() {
+	subscriptionsLock.Lock()
+	defer subscriptionsLock.Unlock()
+
+	if s.closed {
+		return
+	}
+	s.closed = true
+
+	for i, sub := range subscriptions {
+		if sub == s {
+			subscriptions = append(subscriptions[:i], subscriptions[i+1:]...)
+			break
+		}
+	}
+	close(s.events)
+}
+
+// matches reports whether the Subscription wants to be notified about a change to model at key. Delete events carry no Model, so the type is instead read off the key itself (keys are namespaced as ".../TypeName:instance", see GetAndEnsureModel).
+func (s *Subscription) matches(key ds.Key, model Model) bool {
+	if !s.prefix.Equal(key) && !s.prefix.IsAncestorOf(key) {
+		return false
+	}
+	if s.opts.ModelType == "" {
+		return true
+	}
+	if model != nil {
+		return getTypeName(model) == s.opts.ModelType
+	}
+	return key.Type() == s.opts.ModelType
+}
+
+// publish delivers an Event to every Subscription that matches key. Slow subscribers are dropped instead of being allowed to block the caller, which usually still holds a datastore lock.
+func publish(op EventOp, key ds.Key, model Model) {
+	subscriptionsLock.Lock()
+	defer subscriptionsLock.Unlock()
+
+	live := subscriptions[:0]
+	for _, sub := range subscriptions {
+		if !sub.matches(key, model) {
+			live = append(live, sub)
+			continue
+		}
+
+		select {
+		case sub.events <- Event{Op: op, Key: key, Model: model}:
+			live = append(live, sub)
+		default:
+			log.Warningf("database: subscription for %s is too slow, dropping it", sub.prefix)
+			sub.dropped = true
+			sub.closed = true
+			close(sub.events)
+		}
+	}
+	subscriptions = live
+}
+
+// handleCreateSubscriptions notifies subscribers that model was created.
+func handleCreateSubscriptions(model Model) {
+	publish(EventCreate, *model.Key(), model)
+}
+
+// handleUpdateSubscriptions notifies subscribers that model was updated.
+func handleUpdateSubscriptions(model Model) {
+	publish(EventUpdate, *model.Key(), model)
+}
+
+// handleDeleteSubscriptions notifies subscribers that the entry at key was deleted.
+func handleDeleteSubscriptions(key *ds.Key) {
+	publish(EventDelete, *key, nil)
+}
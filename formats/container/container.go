@@ -0,0 +1,227 @@
+// Copyright Safing ICS Technologies GmbH. Use of this source code is governed by the AGPL license that can be found in the LICENSE file.
+
+// Package container provides a length-prefixed message framing primitive built on top of the varint package.
+package container
+
+import (
+	"errors"
+
+	"github.com/Safing/safing-core/formats/varint"
+)
+
+// maxVarintLen is the maximum number of bytes a 64-bit varint can occupy.
+const maxVarintLen = 10
+
+// ErrNotEnoughData is returned by the Get* methods when the Container does not (yet) hold enough data to satisfy the request. Callers should Append more data and retry.
+var ErrNotEnoughData = errors.New("container: not enough data")
+
+// Container holds a sequence of byte compartments that can be queued on either end and consumed from the front without copying the underlying data until it actually becomes necessary.
+type Container struct {
+	compartments [][]byte
+	offset       int
+}
+
+// New creates a new Container from the given byte slices.
+func New(data ...[]byte) *Container {
+	return &Container{compartments: data}
+}
+
+// Length returns the number of unconsumed bytes currently held by the Container.
+func (c *Container) Length() int {
+	length := -c.offset
+	for _, compartment := range c.compartments {
+		length += len(compartment)
+	}
+	return length
+}
+
+// HoldsData returns whether the Container still holds unconsumed data.
+func (c *Container) HoldsData() bool {
+	return c.Length() > 0
+}
+
+// Append adds data to the end of the Container.
+func (c *Container) Append(data []byte) {
+	c.compartments = append(c.compartments, data)
+}
+
+// Prepend adds data to the beginning of the Container.
+func (c *Container) Prepend(data []byte) {
+	c.compartments = append([][]byte{data}, c.compartments...)
+}
+
+// AppendNumber varint-encodes n and appends it to the end of the Container.
+func (c *Container) AppendNumber(n uint64) {
+	c.Append(*varint.Pack64(n))
+}
+
+// PrependNumber varint-encodes n and prepends it to the beginning of the Container.
+func (c *Container) PrependNumber(n uint64) {
+	c.Prepend(*varint.Pack64(n))
+}
+
+// AppendInt varint-encodes n and appends it to the end of the Container.
+func (c *Container) AppendInt(n int) {
+	c.AppendNumber(uint64(n))
+}
+
+// PrependInt varint-encodes n and prepends it to the beginning of the Container.
+func (c *Container) PrependInt(n int) {
+	c.PrependNumber(uint64(n))
+}
+
+// AppendAsBlock appends data to the end of the Container, prefixed with its varint-encoded length.
+func (c *Container) AppendAsBlock(data []byte) {
+	c.AppendNumber(uint64(len(data)))
+	c.Append(data)
+}
+
+// PrependAsBlock prepends data to the beginning of the Container, prefixed with its varint-encoded length.
+func (c *Container) PrependAsBlock(data []byte) {
+	c.Prepend(data)
+	c.PrependNumber(uint64(len(data)))
+}
+
+// CompileData merges all compartments into a single contiguous byte slice, resets the Container to hold just that slice and returns it.
+func (c *Container) CompileData() []byte {
+	data := make([]byte, 0, c.Length())
+	if len(c.compartments) > 0 {
+		data = append(data, c.compartments[0][c.offset:]...)
+		for _, compartment := range c.compartments[1:] {
+			data = append(data, compartment...)
+		}
+	}
+	c.compartments = [][]byte{data}
+	c.offset = 0
+	return data
+}
+
+// ensureContiguous guarantees that the first compartment holds at least n unread bytes by merging subsequent compartments into it as needed. It returns ErrNotEnoughData if the Container does not hold n bytes in total.
+func (c *Container) ensureContiguous(n int) error {
+	if n <= 0 {
+		return nil
+	}
+	if c.Length() < n {
+		return ErrNotEnoughData
+	}
+	for len(c.compartments[0])-c.offset < n {
+		merged := append(append([]byte{}, c.compartments[0][c.offset:]...), c.compartments[1]...)
+		c.compartments = append([][]byte{merged}, c.compartments[2:]...)
+		c.offset = 0
+	}
+	return nil
+}
+
+// advance drops n consumed bytes from the front of the Container.
+func (c *Container) advance(n int) {
+	c.offset += n
+	for len(c.compartments) > 0 && c.offset >= len(c.compartments[0]) {
+		c.offset -= len(c.compartments[0])
+		c.compartments = c.compartments[1:]
+	}
+	if len(c.compartments) == 0 {
+		c.offset = 0
+	}
+}
+
+// peekVarint returns the raw bytes of the next varint at the front of the Container, without consuming them.
+func (c *Container) peekVarint() ([]byte, error) {
+	avail := c.Length()
+	if avail == 0 {
+		return nil, ErrNotEnoughData
+	}
+	limit := avail
+	if limit > maxVarintLen {
+		limit = maxVarintLen
+	}
+	if err := c.ensureContiguous(limit); err != nil {
+		return nil, err
+	}
+	window := c.compartments[0][c.offset : c.offset+limit]
+	for i, b := range window {
+		if b < 0x80 {
+			return window[:i+1], nil
+		}
+	}
+	if avail < maxVarintLen {
+		return nil, ErrNotEnoughData
+	}
+	return nil, errors.New("container: invalid varint encoding")
+}
+
+// GetNextN8 reads and removes a varint-encoded uint8 from the front of the Container.
+func (c *Container) GetNextN8() (uint8, error) {
+	raw, err := c.peekVarint()
+	if err != nil {
+		return 0, err
+	}
+	n, used, err := varint.Unpack8(&raw)
+	if err != nil {
+		return 0, err
+	}
+	c.advance(used)
+	return n, nil
+}
+
+// GetNextN16 reads and removes a varint-encoded uint16 from the front of the Container.
+func (c *Container) GetNextN16() (uint16, error) {
+	raw, err := c.peekVarint()
+	if err != nil {
+		return 0, err
+	}
+	n, used, err := varint.Unpack16(&raw)
+	if err != nil {
+		return 0, err
+	}
+	c.advance(used)
+	return n, nil
+}
+
+// GetNextN32 reads and removes a varint-encoded uint32 from the front of the Container.
+func (c *Container) GetNextN32() (uint32, error) {
+	raw, err := c.peekVarint()
+	if err != nil {
+		return 0, err
+	}
+	n, used, err := varint.Unpack32(&raw)
+	if err != nil {
+		return 0, err
+	}
+	c.advance(used)
+	return n, nil
+}
+
+// GetNextN64 reads and removes a varint-encoded uint64 from the front of the Container.
+func (c *Container) GetNextN64() (uint64, error) {
+	raw, err := c.peekVarint()
+	if err != nil {
+		return 0, err
+	}
+	n, used, err := varint.Unpack64(&raw)
+	if err != nil {
+		return 0, err
+	}
+	c.advance(used)
+	return n, nil
+}
+
+// GetNextBlock reads and removes a varint-length-prefixed block of data from the front of the Container. The length prefix is only consumed once the full block is confirmed to be present, so a partial block can be retried after more data is Appended without losing track of the stream.
+func (c *Container) GetNextBlock() ([]byte, error) {
+	raw, err := c.peekVarint()
+	if err != nil {
+		return nil, err
+	}
+	blockLen, lengthSize, err := varint.Unpack64(&raw)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.ensureContiguous(lengthSize + int(blockLen)); err != nil {
+		return nil, err
+	}
+
+	c.advance(lengthSize)
+	block := make([]byte, blockLen)
+	copy(block, c.compartments[0][c.offset:c.offset+int(blockLen)])
+	c.advance(int(blockLen))
+	return block, nil
+}
@@ -52,7 +52,7 @@ func Unpack8(b *[]byte) (uint8, int, error) {
 	if blob[1] != 0x01 {
 		return 0, 0, errors.New("varint: encoded integer greater than 255 (uint8)")
 	}
-	return blob[0], 1, nil
+	return blob[0], 2, nil
 }
 
 // Unpack16 unpacks a VarInt into a uint16. It returns the extracted int, how many bytes were used and an error.
@@ -96,3 +96,59 @@ func Unpack64(b *[]byte) (uint64, int, error) {
 	}
 	return n, r, nil
 }
+
+// PackS8 packs an int8 into a zigzag-encoded VarInt.
+func PackS8(n int8) *[]byte {
+	return Pack8(uint8(n<<1) ^ uint8(n>>7))
+}
+
+// PackS16 packs an int16 into a zigzag-encoded VarInt.
+func PackS16(n int16) *[]byte {
+	return Pack16(uint16(n<<1) ^ uint16(n>>15))
+}
+
+// PackS32 packs an int32 into a zigzag-encoded VarInt.
+func PackS32(n int32) *[]byte {
+	return Pack32(uint32(n<<1) ^ uint32(n>>31))
+}
+
+// PackS64 packs an int64 into a zigzag-encoded VarInt.
+func PackS64(n int64) *[]byte {
+	return Pack64(uint64(n<<1) ^ uint64(n>>63))
+}
+
+// UnpackS8 unpacks a zigzag-encoded VarInt into an int8. It returns the extracted int, how many bytes were used and an error.
+func UnpackS8(b *[]byte) (int8, int, error) {
+	n, r, err := Unpack8(b)
+	if err != nil {
+		return 0, 0, err
+	}
+	return int8(n>>1) ^ -int8(n&1), r, nil
+}
+
+// UnpackS16 unpacks a zigzag-encoded VarInt into an int16. It returns the extracted int, how many bytes were used and an error.
+func UnpackS16(b *[]byte) (int16, int, error) {
+	n, r, err := Unpack16(b)
+	if err != nil {
+		return 0, 0, err
+	}
+	return int16(n>>1) ^ -int16(n&1), r, nil
+}
+
+// UnpackS32 unpacks a zigzag-encoded VarInt into an int32. It returns the extracted int, how many bytes were used and an error.
+func UnpackS32(b *[]byte) (int32, int, error) {
+	n, r, err := Unpack32(b)
+	if err != nil {
+		return 0, 0, err
+	}
+	return int32(n>>1) ^ -int32(n&1), r, nil
+}
+
+// UnpackS64 unpacks a zigzag-encoded VarInt into an int64. It returns the extracted int, how many bytes were used and an error.
+func UnpackS64(b *[]byte) (int64, int, error) {
+	n, r, err := Unpack64(b)
+	if err != nil {
+		return 0, 0, err
+	}
+	return int64(n>>1) ^ -int64(n&1), r, nil
+}
@@ -0,0 +1,26 @@
+package bson
+
+// Byte sizes of the fixed-width BSON field types used throughout this package.
+const (
+	sizeofInt32 = 4
+	sizeofInt64 = 8
+)
+
+// ObjectId is a 12-byte BSON ObjectId (type 0x07).
+type ObjectId [12]byte
+
+// Datetime is a BSON UTC datetime (type 0x09), stored as milliseconds since the Unix epoch.
+type Datetime int64
+
+// Timestamp is a BSON internal Timestamp (type 0x11).
+type Timestamp int64
+
+// Marshal encodes v into a BSON document. v must be a map[string]interface{}, or a pointer to one.
+func Marshal(v interface{}) ([]byte, error) {
+	return encode(v)
+}
+
+// Unmarshal decodes BSON-encoded data into v, which must be a non-nil pointer to either a map[string]interface{} or a struct with `bson:"fieldname"` tags. It is equivalent to Decode.
+func Unmarshal(data []byte, v interface{}) error {
+	return Decode(data, v)
+}
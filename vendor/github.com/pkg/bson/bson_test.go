@@ -0,0 +1,34 @@
+package bson
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMarshalUnmarshalRoundTrip(t *testing.T) {
+	in := map[string]interface{}{
+		"name":  "test",
+		"count": int32(42),
+		"big":   int64(1) << 40,
+		"ratio": 3.5,
+		"ok":    true,
+		"tags":  []interface{}{"a", "b"},
+		"nested": map[string]interface{}{
+			"inner": "value",
+		},
+	}
+
+	data, err := Marshal(in)
+	if err != nil {
+		t.Fatalf("Marshal failed: %s", err)
+	}
+
+	out := make(map[string]interface{})
+	if err := Unmarshal(data, &out); err != nil {
+		t.Fatalf("Unmarshal failed: %s", err)
+	}
+
+	if !reflect.DeepEqual(in, out) {
+		t.Fatalf("round-trip mismatch:\nin:  %#v\nout: %#v", in, out)
+	}
+}
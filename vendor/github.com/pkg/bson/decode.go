@@ -0,0 +1,281 @@
+package bson
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"strconv"
+)
+
+// Decode decodes BSON-encoded data into v, which must be a non-nil pointer to
+// either a map[string]interface{} or a struct with `bson:"fieldname"` tags.
+func Decode(data []byte, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return errors.New("bson: Decode requires a non-nil pointer")
+	}
+
+	r := &reader{bson: data}
+	doc, err := r.readDocument()
+	if err != nil {
+		return err
+	}
+	return populate(rv.Elem(), doc)
+}
+
+// reader reads formatted BSON objects.
+type reader struct {
+	bson []byte
+	off  int
+}
+
+// need reports an error if fewer than n bytes remain to be read.
+func (r *reader) need(n int) error {
+	if len(r.bson)-r.off < n {
+		return errors.New("bson: unexpected end of data")
+	}
+	return nil
+}
+
+// readDocument reads a BSON document (or array) starting at the reader's
+// current offset and returns its elements keyed by field name.
+func (r *reader) readDocument() (map[string]interface{}, error) {
+	start := r.off
+	length, err := r.readInt32()
+	if err != nil {
+		return nil, err
+	}
+	end := start + int(length)
+	if length < sizeofInt32+1 || end > len(r.bson) {
+		return nil, errors.New("bson: invalid document length")
+	}
+
+	doc := make(map[string]interface{})
+	for r.off < end-1 {
+		typ, err := r.readType()
+		if err != nil {
+			return nil, err
+		}
+		name, err := r.readCstring()
+		if err != nil {
+			return nil, err
+		}
+		val, err := r.readValue(typ)
+		if err != nil {
+			return nil, err
+		}
+		doc[name] = val
+	}
+	r.off = end // skip the trailing 0x00
+	return doc, nil
+}
+
+// readArray reads a BSON array, which is encoded as a document whose keys
+// are the stringified indices of its elements.
+func (r *reader) readArray() ([]interface{}, error) {
+	doc, err := r.readDocument()
+	if err != nil {
+		return nil, err
+	}
+	arr := make([]interface{}, len(doc))
+	for key, val := range doc {
+		idx, err := strconv.Atoi(key)
+		if err != nil || idx < 0 || idx >= len(arr) {
+			return nil, errors.New("bson: invalid array index: " + key)
+		}
+		arr[idx] = val
+	}
+	return arr, nil
+}
+
+// readValue reads a single element's value according to its type byte.
+func (r *reader) readValue(typ byte) (interface{}, error) {
+	switch typ {
+	case 0x01:
+		return r.readFloat64()
+	case 0x02:
+		return r.readString()
+	case 0x03:
+		return r.readDocument()
+	case 0x04:
+		return r.readArray()
+	case 0x07:
+		return r.readObjectId()
+	case 0x08:
+		return r.readBool()
+	case 0x09:
+		n, err := r.readInt64()
+		return Datetime(n), err
+	case 0x0a:
+		return nil, nil
+	case 0x10:
+		return r.readInt32()
+	case 0x11:
+		n, err := r.readInt64()
+		return Timestamp(n), err
+	case 0x12:
+		return r.readInt64()
+	default:
+		return nil, errors.New("bson: unsupported type: 0x" + strconv.FormatInt(int64(typ), 16))
+	}
+}
+
+func (r *reader) readType() (byte, error) {
+	if err := r.need(1); err != nil {
+		return 0, err
+	}
+	typ := r.bson[r.off]
+	r.off++
+	return typ, nil
+}
+
+func (r *reader) readBool() (bool, error) {
+	if err := r.need(1); err != nil {
+		return false, err
+	}
+	b := r.bson[r.off] != 0x00
+	r.off++
+	return b, nil
+}
+
+func (r *reader) readCstring() (string, error) {
+	end := r.off
+	for end < len(r.bson) && r.bson[end] != 0x00 {
+		end++
+	}
+	if end >= len(r.bson) {
+		return "", errors.New("bson: unterminated cstring")
+	}
+	s := string(r.bson[r.off:end])
+	r.off = end + 1
+	return s, nil
+}
+
+// readString reads a BSON UTF-8 string: an int32 byte length (including the
+// trailing nul) followed by that many bytes.
+func (r *reader) readString() (string, error) {
+	size, err := r.readInt32()
+	if err != nil {
+		return "", err
+	}
+	if size < 1 {
+		return "", errors.New("bson: invalid string length")
+	}
+	if err := r.need(int(size)); err != nil {
+		return "", err
+	}
+	s := string(r.bson[r.off : r.off+int(size)-1])
+	r.off += int(size)
+	return s, nil
+}
+
+func (r *reader) readObjectId() (ObjectId, error) {
+	var id ObjectId
+	if err := r.need(len(id)); err != nil {
+		return id, err
+	}
+	copy(id[:], r.bson[r.off:r.off+len(id)])
+	r.off += len(id)
+	return id, nil
+}
+
+func (r *reader) readInt32() (int32, error) {
+	if err := r.need(sizeofInt32); err != nil {
+		return 0, err
+	}
+	b := r.bson[r.off : r.off+sizeofInt32]
+	v := int32(b[0]) | int32(b[1])<<8 | int32(b[2])<<16 | int32(b[3])<<24
+	r.off += sizeofInt32
+	return v, nil
+}
+
+func (r *reader) readInt64() (int64, error) {
+	if err := r.need(sizeofInt64); err != nil {
+		return 0, err
+	}
+	b := r.bson[r.off : r.off+sizeofInt64]
+	v := int64(b[0]) | int64(b[1])<<8 | int64(b[2])<<16 | int64(b[3])<<24 |
+		int64(b[4])<<32 | int64(b[5])<<40 | int64(b[6])<<48 | int64(b[7])<<56
+	r.off += sizeofInt64
+	return v, nil
+}
+
+func (r *reader) readFloat64() (float64, error) {
+	n, err := r.readInt64()
+	if err != nil {
+		return 0, err
+	}
+	return math.Float64frombits(uint64(n)), nil
+}
+
+// populate assigns the elements of doc onto rv, which must be a
+// map[string]interface{} or a struct with `bson` tags.
+func populate(rv reflect.Value, doc map[string]interface{}) error {
+	switch rv.Kind() {
+	case reflect.Map:
+		if rv.IsNil() {
+			rv.Set(reflect.MakeMap(rv.Type()))
+		}
+		for k, v := range doc {
+			rv.SetMapIndex(reflect.ValueOf(k), reflect.ValueOf(v))
+		}
+		return nil
+	case reflect.Struct:
+		t := rv.Type()
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			tag := field.Tag.Get("bson")
+			if tag == "-" {
+				continue
+			}
+			if tag == "" {
+				tag = field.Name
+			}
+			val, ok := doc[tag]
+			if !ok {
+				continue
+			}
+			if err := assign(rv.Field(i), val); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return errors.New("bson: Decode requires a pointer to a map or struct, got " + rv.Kind().String())
+	}
+}
+
+// assign converts and assigns a decoded value onto a struct field.
+func assign(field reflect.Value, val interface{}) error {
+	if val == nil {
+		return nil
+	}
+
+	if m, ok := val.(map[string]interface{}); ok {
+		if field.Kind() == reflect.Struct {
+			return populate(field, m)
+		}
+	}
+
+	if arr, ok := val.([]interface{}); ok && field.Kind() == reflect.Slice {
+		slice := reflect.MakeSlice(field.Type(), len(arr), len(arr))
+		for i, elem := range arr {
+			if err := assign(slice.Index(i), elem); err != nil {
+				return err
+			}
+		}
+		field.Set(slice)
+		return nil
+	}
+
+	rv := reflect.ValueOf(val)
+	if rv.Type().AssignableTo(field.Type()) {
+		field.Set(rv)
+		return nil
+	}
+	if rv.Type().ConvertibleTo(field.Type()) {
+		field.Set(rv.Convert(field.Type()))
+		return nil
+	}
+	return errors.New("bson: cannot assign " + rv.Type().String() + " to field of type " + field.Type().String())
+}